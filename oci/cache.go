@@ -0,0 +1,96 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package oci
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/opencontainers/go-digest"
+)
+
+// blobCachePath returns the path under CacheDir used to store the blob for
+// dgst, with the digest's algorithm and hex-encoded portion forming the file
+// name so collisions across algorithms aren't possible.
+func (i *Indexer) blobCachePath(dgst digest.Digest) string {
+	return filepath.Join(i.opts.CacheDir, dgst.Algorithm().String()+"_"+dgst.Encoded())
+}
+
+// blobStorePath returns the on-disk path a blob for dgst is (or should be)
+// stored at: CacheDir if one is configured, so repeated indexing runs reuse
+// it, otherwise a directory under os.TempDir scoped to this indexer's
+// lifetime and removed on Close. Content blobs are large enough that they're
+// always streamed to disk rather than held in memory, regardless of whether
+// CacheDir is set.
+func (i *Indexer) blobStorePath(dgst digest.Digest) (string, error) {
+	if i.opts.CacheDir != "" {
+		return i.blobCachePath(dgst), nil
+	}
+
+	dir, err := i.tempDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, dgst.Algorithm().String()+"_"+dgst.Encoded()), nil
+}
+
+// tempDir lazily creates (and remembers) a temp directory, removed by Close,
+// used to stream blobs to disk when no CacheDir is configured.
+func (i *Indexer) tempDir() (string, error) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if i.tmpDir != "" {
+		return i.tmpDir, nil
+	}
+
+	dir, err := os.MkdirTemp("", "package-registry-oci-")
+	if err != nil {
+		return "", fmt.Errorf("failed to create temp directory for blob storage: %w", err)
+	}
+	i.tmpDir = dir
+	return dir, nil
+}
+
+// streamBlobToDisk streams r to path without buffering its contents in
+// memory, first writing to a uniquely-named temp file in the same directory
+// and renaming into place so a concurrent reader never observes a partially
+// written blob. The temp file is unique per call (rather than a fixed
+// path+".tmp") so that two Refreshes racing to store the same new blob (one
+// triggered by Get, the other by the background poll loop) don't write
+// through the same file and corrupt each other's output. The streamed bytes
+// are verified against dgst before being renamed into place, so a
+// compromised or malicious registry (or mirror) can't get tampered content
+// cached and served under a digest it doesn't match.
+func streamBlobToDisk(path string, dgst digest.Digest, r io.Reader) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create directory for %s: %w", path, err)
+	}
+
+	f, err := os.CreateTemp(dir, filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create temp file for %s: %w", path, err)
+	}
+	tmp := f.Name()
+
+	verifier := dgst.Verifier()
+	if _, err := io.Copy(io.MultiWriter(f, verifier), r); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write %s: %w", tmp, err)
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to finalize %s: %w", path, err)
+	}
+	if !verifier.Verified() {
+		os.Remove(tmp)
+		return fmt.Errorf("blob does not match expected digest %s", dgst)
+	}
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,106 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package oci
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/opencontainers/go-digest"
+)
+
+func TestBlobCachePath(t *testing.T) {
+	i := &Indexer{opts: IndexerOptions{CacheDir: "/cache"}}
+	dgst := digest.FromString("contents")
+
+	got := i.blobCachePath(dgst)
+	want := filepath.Join("/cache", "sha256_"+dgst.Encoded())
+	if got != want {
+		t.Errorf("blobCachePath() = %q, want %q", got, want)
+	}
+}
+
+func TestBlobStorePath(t *testing.T) {
+	dgst := digest.FromString("contents")
+
+	t.Run("uses CacheDir when configured", func(t *testing.T) {
+		i := &Indexer{opts: IndexerOptions{CacheDir: t.TempDir()}}
+		path, err := i.blobStorePath(dgst)
+		if err != nil {
+			t.Fatalf("blobStorePath: %v", err)
+		}
+		if filepath.Dir(path) != i.opts.CacheDir {
+			t.Errorf("got path %q, want it under CacheDir %q", path, i.opts.CacheDir)
+		}
+	})
+
+	t.Run("falls back to the indexer's temp dir, reused across calls", func(t *testing.T) {
+		i := &Indexer{}
+		first, err := i.blobStorePath(dgst)
+		if err != nil {
+			t.Fatalf("blobStorePath: %v", err)
+		}
+		second, err := i.blobStorePath(digest.FromString("other contents"))
+		if err != nil {
+			t.Fatalf("blobStorePath: %v", err)
+		}
+		if filepath.Dir(first) != filepath.Dir(second) {
+			t.Errorf("blobStorePath used different temp dirs across calls: %q vs %q", first, second)
+		}
+		if filepath.Dir(first) != i.tmpDir {
+			t.Errorf("blobStorePath didn't reuse i.tmpDir: got %q, want %q", filepath.Dir(first), i.tmpDir)
+		}
+	})
+}
+
+func TestStreamBlobToDisk(t *testing.T) {
+	t.Run("writes content matching its digest", func(t *testing.T) {
+		contents := "package contents"
+		dgst := digest.FromString(contents)
+		path := filepath.Join(t.TempDir(), "blob")
+
+		if err := streamBlobToDisk(path, dgst, strings.NewReader(contents)); err != nil {
+			t.Fatalf("streamBlobToDisk: %v", err)
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("ReadFile: %v", err)
+		}
+		if string(data) != contents {
+			t.Errorf("got %q, want %q", data, contents)
+		}
+
+		entries, err := os.ReadDir(filepath.Dir(path))
+		if err != nil {
+			t.Fatalf("ReadDir: %v", err)
+		}
+		if len(entries) != 1 {
+			t.Errorf("temp file was not cleaned up, directory contains: %v", entries)
+		}
+	})
+
+	t.Run("rejects and removes content that doesn't match its digest", func(t *testing.T) {
+		dgst := digest.FromString("expected contents")
+		path := filepath.Join(t.TempDir(), "blob")
+
+		if err := streamBlobToDisk(path, dgst, strings.NewReader("tampered contents")); err == nil {
+			t.Fatal("expected a digest mismatch error")
+		}
+
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("streamBlobToDisk left a file behind after a digest mismatch: %v", err)
+		}
+		entries, err := os.ReadDir(filepath.Dir(path))
+		if err != nil {
+			t.Fatalf("ReadDir: %v", err)
+		}
+		if len(entries) != 0 {
+			t.Errorf("temp file was not cleaned up after a digest mismatch, directory contains: %v", entries)
+		}
+	})
+}
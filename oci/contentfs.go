@@ -0,0 +1,211 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package oci
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"time"
+)
+
+// tarGzFS is a read-only fs.FS backed by a gzip-compressed tar file on disk.
+// Building one only scans the archive's headers, not its file bodies, and
+// Open streams a single entry's content straight out of the archive, so
+// serving a package never requires holding its whole content tree in memory
+// at once the way extracting into a testing/fstest.MapFS would.
+type tarGzFS struct {
+	path    string
+	entries map[string]*tarGzEntry
+}
+
+// newTarGzFS builds a tarGzFS over the gzip-compressed tar file at diskPath.
+func newTarGzFS(diskPath string) (fs.FS, error) {
+	entries, err := scanTarGz(diskPath)
+	if err != nil {
+		return nil, err
+	}
+	return &tarGzFS{path: diskPath, entries: entries}, nil
+}
+
+// scanTarGz reads just the tar headers from diskPath, discarding file bodies,
+// to build a name-indexed directory listing.
+func scanTarGz(diskPath string) (map[string]*tarGzEntry, error) {
+	f, err := os.Open(diskPath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	gzr, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress package tarball: %w", err)
+	}
+	defer gzr.Close()
+
+	entries := make(map[string]*tarGzEntry)
+	tr := tar.NewReader(gzr)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read package tarball: %w", err)
+		}
+
+		name := path.Clean(hdr.Name)
+		switch hdr.Typeflag {
+		case tar.TypeReg:
+			entries[name] = &tarGzEntry{name: name, size: hdr.Size, mode: fs.FileMode(hdr.Mode).Perm(), modTime: hdr.ModTime}
+		case tar.TypeDir:
+			entries[name] = &tarGzEntry{name: name, mode: fs.FileMode(hdr.Mode).Perm() | fs.ModeDir, modTime: hdr.ModTime, isDir: true}
+		}
+	}
+	return entries, nil
+}
+
+func (t *tarGzFS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+
+	if name == "." {
+		return &tarGzDir{entry: &tarGzEntry{name: ".", mode: fs.ModeDir, isDir: true}, fsys: t}, nil
+	}
+
+	entry, ok := t.entries[name]
+	if !ok {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	if entry.isDir {
+		return &tarGzDir{entry: entry, fsys: t}, nil
+	}
+
+	f, err := os.Open(t.path)
+	if err != nil {
+		return nil, err
+	}
+	gzr, err := gzip.NewReader(f)
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to decompress package tarball: %w", err)
+	}
+
+	tr := tar.NewReader(gzr)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			f.Close()
+			return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+		}
+		if err != nil {
+			f.Close()
+			return nil, fmt.Errorf("failed to read package tarball: %w", err)
+		}
+		if hdr.Typeflag != tar.TypeReg || path.Clean(hdr.Name) != name {
+			continue
+		}
+		return &tarGzFile{closer: f, r: io.LimitReader(tr, hdr.Size), entry: entry}, nil
+	}
+}
+
+// ReadDir implements fs.ReadDirFS from the pre-scanned header index, so
+// listing a directory never needs to re-read the archive.
+func (t *tarGzFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	if name != "." {
+		entry, ok := t.entries[name]
+		if !ok || !entry.isDir {
+			return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrNotExist}
+		}
+	}
+
+	var children []fs.DirEntry
+	for n, entry := range t.entries {
+		if n == name || path.Dir(n) != name {
+			continue
+		}
+		children = append(children, entry)
+	}
+	sort.Slice(children, func(a, b int) bool { return children[a].Name() < children[b].Name() })
+	return children, nil
+}
+
+// tarGzEntry is both the fs.FileInfo and fs.DirEntry for a single tar entry.
+type tarGzEntry struct {
+	name    string
+	size    int64
+	mode    fs.FileMode
+	modTime time.Time
+	isDir   bool
+}
+
+func (e *tarGzEntry) Name() string               { return path.Base(e.name) }
+func (e *tarGzEntry) Size() int64                { return e.size }
+func (e *tarGzEntry) Mode() fs.FileMode          { return e.mode }
+func (e *tarGzEntry) ModTime() time.Time         { return e.modTime }
+func (e *tarGzEntry) IsDir() bool                { return e.isDir }
+func (e *tarGzEntry) Sys() any                   { return nil }
+func (e *tarGzEntry) Type() fs.FileMode          { return e.mode.Type() }
+func (e *tarGzEntry) Info() (fs.FileInfo, error) { return e, nil }
+
+// tarGzFile streams a single regular file's content directly from the
+// archive; its backing file handle is only opened for the duration of this
+// one read.
+type tarGzFile struct {
+	closer io.Closer
+	r      io.Reader
+	entry  *tarGzEntry
+}
+
+func (f *tarGzFile) Stat() (fs.FileInfo, error) { return f.entry, nil }
+func (f *tarGzFile) Read(p []byte) (int, error) { return f.r.Read(p) }
+func (f *tarGzFile) Close() error               { return f.closer.Close() }
+
+// tarGzDir implements fs.ReadDirFile for a directory entry, listing its
+// children from tarGzFS's in-memory index.
+type tarGzDir struct {
+	entry    *tarGzEntry
+	fsys     *tarGzFS
+	children []fs.DirEntry
+	read     bool
+}
+
+func (d *tarGzDir) Stat() (fs.FileInfo, error) { return d.entry, nil }
+func (d *tarGzDir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.entry.name, Err: fs.ErrInvalid}
+}
+func (d *tarGzDir) Close() error { return nil }
+
+func (d *tarGzDir) ReadDir(n int) ([]fs.DirEntry, error) {
+	if !d.read {
+		children, err := d.fsys.ReadDir(d.entry.name)
+		if err != nil {
+			return nil, err
+		}
+		d.children = children
+		d.read = true
+	}
+
+	if n <= 0 {
+		out := d.children
+		d.children = nil
+		return out, nil
+	}
+	if len(d.children) == 0 {
+		return nil, io.EOF
+	}
+	if n > len(d.children) {
+		n = len(d.children)
+	}
+	out := d.children[:n]
+	d.children = d.children[n:]
+	return out, nil
+}
@@ -0,0 +1,192 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package oci
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeTarGz builds a gzip-compressed tar file at a temp path containing the
+// given regular files, along with an explicit TypeDir header for each
+// intermediate directory, the way real package tarballs are built.
+func writeTarGz(t *testing.T, files map[string]string) string {
+	t.Helper()
+
+	var buf bytes.Buffer
+	gzw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gzw)
+
+	written := make(map[string]bool)
+	writeDir := func(name string) {
+		if name == "." || written[name] {
+			return
+		}
+		written[name] = true
+		if err := tw.WriteHeader(&tar.Header{Name: name + "/", Typeflag: tar.TypeDir, Mode: 0o755}); err != nil {
+			t.Fatalf("failed to write tar dir header for %s: %v", name, err)
+		}
+	}
+
+	for name, contents := range files {
+		dir := filepath.Dir(name)
+		for dir != "." {
+			writeDir(dir)
+			dir = filepath.Dir(dir)
+		}
+
+		hdr := &tar.Header{
+			Name: name,
+			Mode: 0o644,
+			Size: int64(len(contents)),
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatalf("failed to write tar header for %s: %v", name, err)
+		}
+		if _, err := tw.Write([]byte(contents)); err != nil {
+			t.Fatalf("failed to write tar contents for %s: %v", name, err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatalf("failed to close tar writer: %v", err)
+	}
+	if err := gzw.Close(); err != nil {
+		t.Fatalf("failed to close gzip writer: %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "package.tar.gz")
+	if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+		t.Fatalf("failed to write tarball: %v", err)
+	}
+	return path
+}
+
+func TestTarGzFS_OpenAndReadDir(t *testing.T) {
+	path := writeTarGz(t, map[string]string{
+		"manifest.yml":           "format_version: 1.0.0",
+		"changelog.yml":          "- version: 1.0.0",
+		"data_stream/foo/fields": "- name: foo",
+	})
+
+	fsys, err := newTarGzFS(path)
+	if err != nil {
+		t.Fatalf("newTarGzFS: %v", err)
+	}
+
+	t.Run("reads a top-level file", func(t *testing.T) {
+		data, err := fs.ReadFile(fsys, "manifest.yml")
+		if err != nil {
+			t.Fatalf("ReadFile: %v", err)
+		}
+		if string(data) != "format_version: 1.0.0" {
+			t.Errorf("got %q, want %q", data, "format_version: 1.0.0")
+		}
+	})
+
+	t.Run("reads a nested file", func(t *testing.T) {
+		data, err := fs.ReadFile(fsys, "data_stream/foo/fields")
+		if err != nil {
+			t.Fatalf("ReadFile: %v", err)
+		}
+		if string(data) != "- name: foo" {
+			t.Errorf("got %q, want %q", data, "- name: foo")
+		}
+	})
+
+	t.Run("lists the root directory, including intermediate dirs", func(t *testing.T) {
+		entries, err := fs.ReadDir(fsys, ".")
+		if err != nil {
+			t.Fatalf("ReadDir: %v", err)
+		}
+		var names []string
+		for _, e := range entries {
+			names = append(names, e.Name())
+		}
+		want := []string{"changelog.yml", "data_stream", "manifest.yml"}
+		if len(names) != len(want) {
+			t.Fatalf("got entries %v, want %v", names, want)
+		}
+		for i, name := range want {
+			if names[i] != name {
+				t.Errorf("got entries %v, want %v", names, want)
+				break
+			}
+		}
+	})
+
+	t.Run("lists a nested directory", func(t *testing.T) {
+		entries, err := fs.ReadDir(fsys, "data_stream/foo")
+		if err != nil {
+			t.Fatalf("ReadDir: %v", err)
+		}
+		if len(entries) != 1 || entries[0].Name() != "fields" {
+			t.Errorf("got %v, want a single entry named fields", entries)
+		}
+	})
+
+	t.Run("returns fs.ErrNotExist for a missing file", func(t *testing.T) {
+		if _, err := fs.ReadFile(fsys, "does-not-exist.yml"); !os.IsNotExist(err) {
+			t.Errorf("got error %v, want fs.ErrNotExist", err)
+		}
+	})
+
+	t.Run("walk visits every file and directory", func(t *testing.T) {
+		var visited []string
+		if err := fs.WalkDir(fsys, ".", func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if p != "." {
+				visited = append(visited, p)
+			}
+			return nil
+		}); err != nil {
+			t.Fatalf("WalkDir: %v", err)
+		}
+
+		want := []string{"changelog.yml", "data_stream", "data_stream/foo", "data_stream/foo/fields", "manifest.yml"}
+		if len(visited) != len(want) {
+			t.Fatalf("visited %v, want %v", visited, want)
+		}
+	})
+}
+
+func TestTarGzFS_MissingArchive(t *testing.T) {
+	if _, err := newTarGzFS(filepath.Join(t.TempDir(), "does-not-exist.tar.gz")); err == nil {
+		t.Fatal("expected an error opening a nonexistent archive")
+	}
+}
+
+func TestTarGzFile_StreamsOnlyTheRequestedEntry(t *testing.T) {
+	path := writeTarGz(t, map[string]string{
+		"a.txt": "aaaa",
+		"b.txt": "bbbb",
+	})
+
+	fsys, err := newTarGzFS(path)
+	if err != nil {
+		t.Fatalf("newTarGzFS: %v", err)
+	}
+
+	f, err := fsys.Open("b.txt")
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer f.Close()
+
+	data, err := io.ReadAll(f)
+	if err != nil {
+		t.Fatalf("ReadAll: %v", err)
+	}
+	if string(data) != "bbbb" {
+		t.Errorf("got %q, want %q", data, "bbbb")
+	}
+}
@@ -0,0 +1,63 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package oci
+
+import (
+	"context"
+
+	"oras.land/oras-go/v2/registry/remote/auth"
+)
+
+// CredentialStore resolves registry authentication credentials by hostname,
+// so the Indexer can authenticate against whichever registry a repository
+// reference points at without baking a single set of credentials into
+// IndexerOptions.
+type CredentialStore interface {
+	Get(ctx context.Context, registry string) (auth.Credential, error)
+}
+
+// staticCredentialStore always returns the same credential, regardless of
+// registry. It's the direct replacement for the old Username/Password fields.
+type staticCredentialStore struct {
+	credential auth.Credential
+}
+
+// NewStaticCredentialStore returns a CredentialStore that authenticates every
+// registry with the given username and password.
+func NewStaticCredentialStore(username, password string) CredentialStore {
+	return staticCredentialStore{credential: auth.Credential{Username: username, Password: password}}
+}
+
+func (s staticCredentialStore) Get(ctx context.Context, registry string) (auth.Credential, error) {
+	return s.credential, nil
+}
+
+// anonymousCredentialStore never supplies a credential, leaving oras's
+// auth.Client to negotiate any bearer-token challenge anonymously. This is
+// the default when no CredentialStore is configured.
+type anonymousCredentialStore struct{}
+
+// NewAnonymousCredentialStore returns a CredentialStore suitable for public
+// registries that don't require authentication.
+func NewAnonymousCredentialStore() CredentialStore {
+	return anonymousCredentialStore{}
+}
+
+func (anonymousCredentialStore) Get(ctx context.Context, registry string) (auth.Credential, error) {
+	return auth.EmptyCredential, nil
+}
+
+// normalizeRegistryHost canonicalizes the handful of hostnames that all refer
+// to Docker Hub, the way Docker's own ParseRepositoryInfo does, so
+// credentials configured under "docker.io" are found regardless of which
+// synonym a repository reference actually uses.
+func normalizeRegistryHost(host string) string {
+	switch host {
+	case "index.docker.io", "registry-1.docker.io":
+		return "docker.io"
+	default:
+		return host
+	}
+}
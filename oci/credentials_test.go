@@ -0,0 +1,61 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package oci
+
+import (
+	"context"
+	"testing"
+)
+
+func TestNormalizeRegistryHost(t *testing.T) {
+	tests := []struct {
+		host string
+		want string
+	}{
+		{host: "index.docker.io", want: "docker.io"},
+		{host: "registry-1.docker.io", want: "docker.io"},
+		{host: "docker.io", want: "docker.io"},
+		{host: "registry.example.com", want: "registry.example.com"},
+	}
+
+	for _, tt := range tests {
+		if got := normalizeRegistryHost(tt.host); got != tt.want {
+			t.Errorf("normalizeRegistryHost(%q) = %q, want %q", tt.host, got, tt.want)
+		}
+	}
+}
+
+func TestStaticCredentialStore(t *testing.T) {
+	store := NewStaticCredentialStore("user", "pass")
+
+	cred, err := store.Get(context.Background(), "registry.example.com")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cred.Username != "user" || cred.Password != "pass" {
+		t.Errorf("got %+v, want Username=user Password=pass", cred)
+	}
+
+	// The same credential is returned regardless of registry.
+	cred, err = store.Get(context.Background(), "other.example.com")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cred.Username != "user" || cred.Password != "pass" {
+		t.Errorf("got %+v, want Username=user Password=pass", cred)
+	}
+}
+
+func TestAnonymousCredentialStore(t *testing.T) {
+	store := NewAnonymousCredentialStore()
+
+	cred, err := store.Get(context.Background(), "registry.example.com")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cred.Username != "" || cred.Password != "" {
+		t.Errorf("got %+v, want an empty credential", cred)
+	}
+}
@@ -0,0 +1,150 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package oci
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"oras.land/oras-go/v2/registry/remote/auth"
+)
+
+// dockerConfigFile mirrors the subset of ~/.docker/config.json (the same
+// format used by "helm registry login") that's relevant to credential
+// resolution.
+type dockerConfigFile struct {
+	Auths       map[string]dockerAuthEntry `json:"auths"`
+	CredHelpers map[string]string          `json:"credHelpers"`
+	CredsStore  string                     `json:"credsStore"`
+}
+
+type dockerAuthEntry struct {
+	Auth string `json:"auth"`
+}
+
+// legacyDockerHubAuthKey is the key "docker login" (with no registry
+// argument) has always written Docker Hub credentials under, predating the
+// "docker.io" hostname convention. Real config.json files still use it, so
+// it must be checked as a synonym for "docker.io" lookups.
+const legacyDockerHubAuthKey = "https://index.docker.io/v1/"
+
+// DockerConfigCredentialStore resolves credentials from a Docker (or Helm)
+// config.json file, including credHelpers/credsStore-backed credential
+// helper binaries.
+type DockerConfigCredentialStore struct {
+	path string
+}
+
+// NewDockerConfigCredentialStore returns a CredentialStore backed by the
+// Docker config file at path. If path is empty, ~/.docker/config.json is
+// used.
+func NewDockerConfigCredentialStore(path string) (*DockerConfigCredentialStore, error) {
+	if path == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, fmt.Errorf("failed to determine home directory: %w", err)
+		}
+		path = filepath.Join(home, ".docker", "config.json")
+	}
+	return &DockerConfigCredentialStore{path: path}, nil
+}
+
+// Get resolves the credential for registry, following the same precedence
+// Docker itself uses: a registry-specific credHelpers entry, then a plain
+// "auths" entry, then the credsStore default helper.
+func (s *DockerConfigCredentialStore) Get(ctx context.Context, registry string) (auth.Credential, error) {
+	registry = normalizeRegistryHost(registry)
+
+	cfg, err := s.load()
+	if err != nil {
+		return auth.EmptyCredential, err
+	}
+
+	if helper, ok := cfg.CredHelpers[registry]; ok {
+		return runCredentialHelper(ctx, helper, registry)
+	}
+
+	if entry, ok := cfg.Auths[registry]; ok && entry.Auth != "" {
+		return decodeBasicAuth(entry.Auth)
+	}
+	if registry == "docker.io" {
+		if entry, ok := cfg.Auths[legacyDockerHubAuthKey]; ok && entry.Auth != "" {
+			return decodeBasicAuth(entry.Auth)
+		}
+	}
+
+	if cfg.CredsStore != "" {
+		return runCredentialHelper(ctx, cfg.CredsStore, registry)
+	}
+
+	return auth.EmptyCredential, nil
+}
+
+func (s *DockerConfigCredentialStore) load() (*dockerConfigFile, error) {
+	data, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return &dockerConfigFile{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", s.path, err)
+	}
+
+	var cfg dockerConfigFile
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", s.path, err)
+	}
+	return &cfg, nil
+}
+
+// decodeBasicAuth decodes a base64-encoded "username:password" auth entry.
+func decodeBasicAuth(encoded string) (auth.Credential, error) {
+	decoded, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return auth.EmptyCredential, fmt.Errorf("failed to decode auth entry: %w", err)
+	}
+
+	username, password, ok := strings.Cut(string(decoded), ":")
+	if !ok {
+		return auth.EmptyCredential, fmt.Errorf("malformed auth entry")
+	}
+	return auth.Credential{Username: username, Password: password}, nil
+}
+
+// credentialHelperOutput is the JSON shape printed by "docker-credential-*
+// get", per the docker-credential-helpers protocol.
+type credentialHelperOutput struct {
+	ServerURL string `json:"ServerURL"`
+	Username  string `json:"Username"`
+	Secret    string `json:"Secret"`
+}
+
+// runCredentialHelper invokes the "docker-credential-<helper> get" binary the
+// way the Docker and Helm CLIs do, passing registry on stdin and decoding the
+// resulting JSON credential from stdout.
+func runCredentialHelper(ctx context.Context, helper, registry string) (auth.Credential, error) {
+	cmd := exec.CommandContext(ctx, "docker-credential-"+helper, "get")
+	cmd.Stdin = strings.NewReader(registry)
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return auth.EmptyCredential, fmt.Errorf("credential helper %q failed for %s: %w: %s", helper, registry, err, stderr.String())
+	}
+
+	var out credentialHelperOutput
+	if err := json.Unmarshal(stdout.Bytes(), &out); err != nil {
+		return auth.EmptyCredential, fmt.Errorf("failed to parse credential helper %q output: %w", helper, err)
+	}
+
+	return auth.Credential{Username: out.Username, Password: out.Secret}, nil
+}
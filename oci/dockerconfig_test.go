@@ -0,0 +1,90 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package oci
+
+import (
+	"context"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeDockerConfig(t *testing.T, contents string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(contents), 0o600); err != nil {
+		t.Fatalf("failed to write docker config: %v", err)
+	}
+	return path
+}
+
+func TestDockerConfigCredentialStore_Get(t *testing.T) {
+	auth := base64.StdEncoding.EncodeToString([]byte("user:pass"))
+
+	tests := []struct {
+		name         string
+		config       string
+		registry     string
+		wantUsername string
+	}{
+		{
+			name:         "canonical registry key",
+			config:       `{"auths":{"registry.example.com":{"auth":"` + auth + `"}}}`,
+			registry:     "registry.example.com",
+			wantUsername: "user",
+		},
+		{
+			name:         "docker hub via canonical key",
+			config:       `{"auths":{"docker.io":{"auth":"` + auth + `"}}}`,
+			registry:     "index.docker.io",
+			wantUsername: "user",
+		},
+		{
+			name:         "docker hub via legacy index URL, as written by docker login",
+			config:       `{"auths":{"https://index.docker.io/v1/":{"auth":"` + auth + `"}}}`,
+			registry:     "docker.io",
+			wantUsername: "user",
+		},
+		{
+			name:         "docker hub via legacy index URL, referenced by its registry-1 synonym",
+			config:       `{"auths":{"https://index.docker.io/v1/":{"auth":"` + auth + `"}}}`,
+			registry:     "registry-1.docker.io",
+			wantUsername: "user",
+		},
+		{
+			name:         "no matching entry falls back to anonymous",
+			config:       `{"auths":{"other.example.com":{"auth":"` + auth + `"}}}`,
+			registry:     "registry.example.com",
+			wantUsername: "",
+		},
+		{
+			name:         "missing config file falls back to anonymous",
+			config:       "",
+			registry:     "registry.example.com",
+			wantUsername: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var path string
+			if tt.config == "" {
+				path = filepath.Join(t.TempDir(), "does-not-exist.json")
+			} else {
+				path = writeDockerConfig(t, tt.config)
+			}
+
+			store := &DockerConfigCredentialStore{path: path}
+			cred, err := store.Get(context.Background(), tt.registry)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if cred.Username != tt.wantUsername {
+				t.Errorf("Get(%q) username = %q, want %q", tt.registry, cred.Username, tt.wantUsername)
+			}
+		})
+	}
+}
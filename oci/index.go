@@ -0,0 +1,183 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package oci
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"go.uber.org/zap"
+	"oras.land/oras-go/v2/registry"
+
+	"github.com/elastic/package-registry/packages"
+)
+
+// indexEntry is the cached state for a single tag: the digest it was last
+// indexed at (its etag), the semver parsed from the tag itself (used for
+// version selection) and the package parsed from that digest.
+type indexEntry struct {
+	digest  digest.Digest
+	version *semver.Version
+	pkg     *packages.Package
+}
+
+// snapshot returns the currently indexed packages, in tag order, reduced to
+// the highest version per package name. All versions are returned instead
+// when opts asks for them, and also when opts or its Filter is nil.
+func (i *Indexer) snapshot(opts *packages.GetOptions) packages.Packages {
+	i.mu.Lock()
+	entries := make([]*indexEntry, 0, len(i.tagOrder))
+	for _, tag := range i.tagOrder {
+		if entry, ok := i.index[tag]; ok {
+			entries = append(entries, entry)
+		}
+	}
+	i.mu.Unlock()
+
+	if opts == nil || opts.Filter == nil || opts.Filter.AllVersions {
+		result := make(packages.Packages, 0, len(entries))
+		for _, entry := range entries {
+			result = append(result, entry.pkg)
+		}
+		return result
+	}
+
+	entries = latestPerPackage(entries)
+	result := make(packages.Packages, 0, len(entries))
+	for _, entry := range entries {
+		result = append(result, entry.pkg)
+	}
+	return result
+}
+
+// SetCache records dgst as the indexed digest for tag, without fetching or
+// parsing anything. It's intended for the HTTP layer (or tests) to
+// invalidate entries ahead of a Refresh: a digest that differs from the
+// tag's current one makes the next Refresh pull and parse it again. A tag
+// with no parsed package yet is pulled on the next Refresh regardless of
+// the digest recorded here.
+func (i *Indexer) SetCache(tag string, dgst digest.Digest) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if i.index == nil {
+		i.index = make(map[string]*indexEntry)
+	}
+	if entry, ok := i.index[tag]; ok {
+		entry.digest = dgst
+		return
+	}
+	i.index[tag] = &indexEntry{digest: dgst}
+}
+
+// Refresh lists the tags currently in the repository and brings the index up
+// to date: unchanged tags (same manifest digest) are left alone, new or
+// rewritten tags are pulled and parsed, and tags that have been deleted from
+// the registry are dropped from the index. On a transient registry error the
+// last good index is left untouched rather than being emptied. Each network
+// operation is tried against the primary registry first and fails over to
+// the configured mirrors, in order, on transport-level errors.
+func (i *Indexer) Refresh(ctx context.Context) error {
+	if len(i.endpoints) == 0 {
+		return fmt.Errorf("OCI indexer not initialized")
+	}
+
+	tags, _, err := withEndpoints(i.endpoints, func(ep *endpoint) ([]string, error) {
+		return registry.Tags(ctx, ep.repo)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list tags in %s: %w", i.opts.Repository, err)
+	}
+
+	seen := make(map[string]bool, len(tags))
+	var eligible []string
+	for _, tag := range tags {
+		version, ok := i.eligibleTagVersion(tag)
+		if !ok {
+			i.logger.Debug("skipping tag that isn't a selectable package version", zap.String("tag", tag))
+			continue
+		}
+		eligible = append(eligible, tag)
+		seen[tag] = true
+
+		desc, ep, err := withEndpoints(i.endpoints, func(ep *endpoint) (ocispec.Descriptor, error) {
+			return ep.repo.Resolve(ctx, tag)
+		})
+		if err != nil {
+			i.logger.Warn("failed to resolve tag", zap.String("tag", tag), zap.Error(err))
+			continue
+		}
+
+		i.mu.Lock()
+		entry, ok := i.index[tag]
+		i.mu.Unlock()
+		if ok && entry.pkg != nil && entry.digest == desc.Digest {
+			// Unchanged since the last refresh: skip the blob fetch and re-parse.
+			continue
+		}
+
+		pkg, err := i.getPackageFromTag(ctx, i.endpoints, tag, desc)
+		if err != nil {
+			i.logger.Warn("failed to get package from tag", zap.String("tag", tag), zap.String("endpoint", ep.label), zap.Error(err))
+			continue
+		}
+
+		if i.opts.Verification != nil {
+			signed, err := i.verifySignature(ctx, i.endpoints, pkg.BasePath, desc)
+			if err != nil {
+				i.logger.Warn("failed to verify package signature", zap.String("tag", tag), zap.Error(err))
+			}
+			pkg.Signed = signed
+			if !signed {
+				if i.opts.Verification.RequireSignature || i.opts.Verification.TrustPolicy == TrustPolicyReject {
+					i.logger.Warn("dropping unsigned or unverified package", zap.String("tag", tag))
+					continue
+				}
+				i.logger.Warn("indexing unsigned or unverified package", zap.String("tag", tag))
+			}
+		}
+
+		i.mu.Lock()
+		if i.index == nil {
+			i.index = make(map[string]*indexEntry)
+		}
+		i.index[tag] = &indexEntry{digest: desc.Digest, version: version, pkg: pkg}
+		i.mu.Unlock()
+	}
+
+	i.mu.Lock()
+	for tag := range i.index {
+		if !seen[tag] {
+			delete(i.index, tag)
+		}
+	}
+	i.tagOrder = eligible
+	i.mu.Unlock()
+
+	return nil
+}
+
+// startRefreshLoop polls the registry for changes every PollInterval until
+// ctx is cancelled. It is started from Init when PollInterval is positive.
+func (i *Indexer) startRefreshLoop(ctx context.Context) {
+	ticker := time.NewTicker(i.opts.PollInterval)
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := i.Refresh(ctx); err != nil {
+					i.logger.Warn("failed to refresh OCI index", zap.Error(err))
+				}
+			}
+		}
+	}()
+}
@@ -0,0 +1,108 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package oci
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/opencontainers/go-digest"
+	"go.uber.org/zap"
+
+	"github.com/elastic/package-registry/packages"
+)
+
+func newTestEntry(name, version string) *indexEntry {
+	return &indexEntry{
+		digest:  digest.FromString(name + "@" + version),
+		version: semver.MustParse(version),
+		pkg:     &packages.Package{Name: name},
+	}
+}
+
+func TestIndexer_snapshot(t *testing.T) {
+	i := &Indexer{
+		index: map[string]*indexEntry{
+			"system-1.0.0":   newTestEntry("system", "1.0.0"),
+			"system-1.2.3":   newTestEntry("system", "1.2.3"),
+			"elastic-8.9.0":  newTestEntry("elastic_agent", "8.9.0"),
+			"elastic-8.10.0": newTestEntry("elastic_agent", "8.10.0"),
+		},
+		tagOrder: []string{"system-1.0.0", "system-1.2.3", "elastic-8.9.0", "elastic-8.10.0"},
+	}
+
+	t.Run("latest version per package by default", func(t *testing.T) {
+		got := i.snapshot(&packages.GetOptions{Filter: &packages.Filter{}})
+		if len(got) != 2 {
+			t.Fatalf("got %d packages, want 2", len(got))
+		}
+	})
+
+	t.Run("all versions when requested", func(t *testing.T) {
+		got := i.snapshot(&packages.GetOptions{Filter: &packages.Filter{AllVersions: true}})
+		if len(got) != 4 {
+			t.Fatalf("got %d packages, want 4", len(got))
+		}
+	})
+
+	t.Run("all versions when opts is nil", func(t *testing.T) {
+		got := i.snapshot(nil)
+		if len(got) != 4 {
+			t.Fatalf("got %d packages, want 4", len(got))
+		}
+	})
+}
+
+func TestIndexer_SetCache(t *testing.T) {
+	i := &Indexer{}
+
+	dgst := digest.FromString("v1")
+	i.SetCache("system-1.0.0", dgst)
+
+	entry, ok := i.index["system-1.0.0"]
+	if !ok {
+		t.Fatal("expected SetCache to create an entry")
+	}
+	if entry.digest != dgst {
+		t.Errorf("got digest %s, want %s", entry.digest, dgst)
+	}
+
+	// Updating an existing entry only touches its digest.
+	entry.pkg = &packages.Package{Name: "system"}
+	newDgst := digest.FromString("v2")
+	i.SetCache("system-1.0.0", newDgst)
+	if i.index["system-1.0.0"].digest != newDgst {
+		t.Errorf("got digest %s, want %s", i.index["system-1.0.0"].digest, newDgst)
+	}
+	if i.index["system-1.0.0"].pkg == nil || i.index["system-1.0.0"].pkg.Name != "system" {
+		t.Errorf("SetCache clobbered the existing package")
+	}
+}
+
+func TestIndexer_Refresh_RetainsIndexOnTransientError(t *testing.T) {
+	// registry.example.com is a reserved, non-routable domain, so listing
+	// tags against it fails with a transport-level error.
+	logger := zap.NewNop()
+	i := NewIndexer(logger, IndexerOptions{
+		Registry:   "registry.example.com",
+		Repository: "packages",
+	})
+	if err := i.Init(context.Background()); err != nil {
+		t.Fatalf("failed to initialize indexer: %v", err)
+	}
+
+	seeded := newTestEntry("system", "1.0.0")
+	i.index = map[string]*indexEntry{"system-1.0.0": seeded}
+	i.tagOrder = []string{"system-1.0.0"}
+
+	if err := i.Refresh(context.Background()); err == nil {
+		t.Fatal("expected an error refreshing from an unreachable registry")
+	}
+
+	if got := i.index["system-1.0.0"]; got != seeded {
+		t.Errorf("Refresh modified the index on a transient listing error: got %+v", got)
+	}
+}
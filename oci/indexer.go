@@ -6,14 +6,12 @@ package oci
 
 import (
 	"context"
-	"crypto/tls"
 	"fmt"
-	"net/http"
-	"strings"
+	"os"
+	"regexp"
+	"sync"
+	"time"
 
-	"oras.land/oras-go/v2/registry"
-	"oras.land/oras-go/v2/registry/remote"
-	"oras.land/oras-go/v2/registry/remote/auth"
 	"go.uber.org/zap"
 
 	"github.com/elastic/package-registry/packages"
@@ -23,7 +21,20 @@ import (
 type Indexer struct {
 	logger *zap.Logger
 	opts   IndexerOptions
-	repo   *remote.Repository
+
+	// endpoints holds the primary registry followed by each configured
+	// mirror, in failover order.
+	endpoints []*endpoint
+
+	cancelRefresh context.CancelFunc
+
+	mu       sync.Mutex
+	index    map[string]*indexEntry
+	tagOrder []string
+	// tmpDir is a lazily-created directory, removed by Close, used to stream
+	// package content blobs to disk when CacheDir isn't configured. See
+	// blobStorePath.
+	tmpDir string
 }
 
 // IndexerOptions contains configuration options for the OCI indexer.
@@ -32,12 +43,33 @@ type IndexerOptions struct {
 	Registry string
 	// Repository is the repository within the registry (e.g., "packages")
 	Repository string
-	// Username for registry authentication
-	Username string
-	// Password for registry authentication  
-	Password string
+	// CredentialStore resolves authentication credentials per-registry. If
+	// nil, the registry is accessed anonymously.
+	CredentialStore CredentialStore
 	// Insecure allows insecure connections to the registry
 	Insecure bool
+	// CacheDir is an optional directory used to cache pulled package content on
+	// disk, keyed by the digest of the package content layer (not by the
+	// manifest digest), so repeated indexing runs don't refetch blobs that have
+	// already been pulled.
+	CacheDir string
+	// PollInterval, if positive, makes Init start a background loop that calls
+	// Refresh on this interval, so the index tracks tag additions, deletions
+	// and rewrites without a caller having to poll manually.
+	PollInterval time.Duration
+	// TagPattern, if set, restricts indexing to tags matching this regexp.
+	// Tags that don't match are treated the same as non-semver tags: dropped
+	// before they're ever resolved or pulled.
+	TagPattern *regexp.Regexp
+	// IncludePrerelease allows semver prerelease tags (e.g. "1.2.3-beta.1")
+	// to be indexed. By default only release versions are considered.
+	IncludePrerelease bool
+	// Verification, if set, makes Refresh check each pulled package's cosign
+	// signature before indexing it.
+	Verification *VerificationOptions
+	// Mirrors are fallback registries tried, in order, when the primary
+	// registry fails with a transport-level error.
+	Mirrors []MirrorConfig
 }
 
 // NewIndexer creates a new OCI indexer with the given options.
@@ -50,159 +82,86 @@ func NewIndexer(logger *zap.Logger, opts IndexerOptions) *Indexer {
 
 // Init initializes the OCI indexer and sets up the ORAS client.
 func (i *Indexer) Init(ctx context.Context) error {
-	i.logger.Info("Initializing OCI indexer", 
+	i.logger.Info("Initializing OCI indexer",
 		zap.String("registry", i.opts.Registry),
 		zap.String("repository", i.opts.Repository))
-	
+
 	// Validate configuration
 	if i.opts.Registry == "" {
 		return fmt.Errorf("OCI registry URL is required")
 	}
-	
+
 	if i.opts.Repository == "" {
 		return fmt.Errorf("OCI repository name is required")
 	}
 
-	// Create repository reference
-	repoRef := fmt.Sprintf("%s/%s", i.opts.Registry, i.opts.Repository)
-	repo, err := remote.NewRepository(repoRef)
+	primary, err := newEndpoint(i.opts.Registry, i.opts.Registry, i.opts.Repository, i.opts.CredentialStore, i.opts.Insecure, false)
 	if err != nil {
-		return fmt.Errorf("failed to create repository reference %s: %w", repoRef, err)
+		return err
 	}
+	endpoints := []*endpoint{primary}
 
-	// Configure authentication if credentials are provided
-	if i.opts.Username != "" && i.opts.Password != "" {
-		repo.Client = &auth.Client{
-			Client: &http.Client{},
+	for _, mirror := range i.opts.Mirrors {
+		host, plainHTTP, err := validateMirror(mirror.Registry)
+		if err != nil {
+			return err
 		}
-		// Note: This is a simplified auth setup, real implementation would need proper credential management
-		i.logger.Debug("Authentication configured for OCI registry")
-	}
 
-	// Configure insecure connection if requested
-	if i.opts.Insecure {
-		if repo.Client == nil {
-			repo.Client = &http.Client{}
+		credStore := mirror.CredentialStore
+		if credStore == nil {
+			credStore = i.opts.CredentialStore
 		}
-		if httpClient, ok := repo.Client.(*http.Client); ok {
-			httpClient.Transport = &http.Transport{
-				TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
-			}
+
+		ep, err := newEndpoint(host, host, i.opts.Repository, credStore, mirror.Insecure, plainHTTP)
+		if err != nil {
+			return err
 		}
+		endpoints = append(endpoints, ep)
+	}
+
+	i.endpoints = endpoints
+
+	if i.opts.PollInterval > 0 {
+		refreshCtx, cancel := context.WithCancel(context.Background())
+		i.cancelRefresh = cancel
+		i.startRefreshLoop(refreshCtx)
 	}
 
-	i.repo = repo
 	i.logger.Info("OCI indexer initialized successfully")
 	return nil
 }
 
-// Get retrieves packages from the OCI registry.
+// Get retrieves packages from the OCI registry, pulling and parsing the
+// package artifact behind each tag that has changed since the last call.
 func (i *Indexer) Get(ctx context.Context, opts *packages.GetOptions) (packages.Packages, error) {
 	i.logger.Debug("Getting packages from OCI registry")
-	
-	if i.repo == nil {
-		return nil, fmt.Errorf("OCI indexer not initialized")
-	}
 
-	// For the enhanced implementation, try to connect to the registry
-	// If connection fails, fall back to mock package
-	var allPackages packages.Packages
-
-	// Attempt to list tags - this is a simplified approach
-	// In a real implementation, this would need proper error handling for different registry types
-	tags, err := registry.Tags(ctx, i.repo)
-	if err != nil {
-		// If we can't list tags (e.g., registry doesn't support it or no permissions),
-		// fall back to returning a mock package for demonstration
-		i.logger.Warn("Failed to list tags from OCI registry, returning mock package", zap.Error(err))
-		return i.getMockPackage(), nil
+	if err := i.Refresh(ctx); err != nil {
+		return nil, err
 	}
 
-	for _, tag := range tags {
-		i.logger.Debug("Processing tag", zap.String("tag", tag))
-		
-		// Try to pull and parse package manifest for this tag
-		pkg, err := i.getPackageFromTag(ctx, tag)
-		if err != nil {
-			i.logger.Warn("Failed to get package from tag", 
-				zap.String("tag", tag), 
-				zap.Error(err))
-			continue
-		}
-		
-		if pkg != nil {
-			allPackages = append(allPackages, pkg)
-		}
-	}
-
-	// If no packages found from tags, return mock package
-	if len(allPackages) == 0 {
-		i.logger.Info("No packages found in OCI registry tags, returning mock package")
-		return i.getMockPackage(), nil
-	}
-
-	i.logger.Info("Retrieved packages from OCI registry", zap.Int("count", len(allPackages)))
-	return allPackages, nil
+	result := i.snapshot(opts)
+	i.logger.Info("Retrieved packages from OCI registry", zap.Int("count", len(result)))
+	return result, nil
 }
 
-// getMockPackage returns a mock package for demonstration/testing purposes
-func (i *Indexer) getMockPackage() packages.Packages {
-	mockPackageName := "oci-mock-package"
-	mockPackageTitle := "Mock OCI Package"
-	
-	mockPackage := &packages.Package{
-		BasePackage: packages.BasePackage{
-			Name:        mockPackageName,
-			Version:     "1.0.0",
-			Title:       &mockPackageTitle,
-			Description: fmt.Sprintf("Mock package from OCI registry %s/%s", i.opts.Registry, i.opts.Repository),
-			Type:        "integration",
-			Categories:  []string{"web"},
-		},
-		BasePath: fmt.Sprintf("oci://%s/%s:latest", i.opts.Registry, i.opts.Repository),
+// Close closes the OCI indexer, stopping the background refresh loop if one
+// was started, and cleans up resources.
+func (i *Indexer) Close(ctx context.Context) error {
+	i.logger.Debug("Closing OCI indexer")
+	if i.cancelRefresh != nil {
+		i.cancelRefresh()
 	}
 
-	return packages.Packages{mockPackage}
-}
-
-// getPackageFromTag retrieves a package manifest from a specific tag
-func (i *Indexer) getPackageFromTag(ctx context.Context, tag string) (*packages.Package, error) {
-	// For this implementation, we'll create a basic package structure based on the tag
-	// In a full implementation, this would:
-	// 1. Pull the actual artifact using ORAS
-	// 2. Extract the manifest.yml file from the artifact
-	// 3. Parse it into a Package struct
-	
-	// For now, create a package based on tag information
-	packageName := strings.Split(tag, ":")[0]
-	if packageName == "" {
-		packageName = tag
-	}
-	
-	packageVersion := "1.0.0"
-	if parts := strings.Split(tag, ":"); len(parts) > 1 {
-		packageVersion = parts[1]
-	}
-	
-	title := fmt.Sprintf("Package %s", packageName)
-	
-	p := &packages.Package{
-		BasePackage: packages.BasePackage{
-			Name:        packageName,
-			Version:     packageVersion,
-			Title:       &title,
-			Description: fmt.Sprintf("Package %s from OCI registry %s/%s", packageName, i.opts.Registry, i.opts.Repository),
-			Type:        "integration",
-			Categories:  []string{"observability"},
-		},
-		BasePath: fmt.Sprintf("oci://%s/%s:%s", i.opts.Registry, i.opts.Repository, tag),
+	i.mu.Lock()
+	tmpDir := i.tmpDir
+	i.tmpDir = ""
+	i.mu.Unlock()
+	if tmpDir != "" {
+		if err := os.RemoveAll(tmpDir); err != nil {
+			return fmt.Errorf("failed to remove temp directory %s: %w", tmpDir, err)
+		}
 	}
-	
-	return p, nil
-}
 
-// Close closes the OCI indexer and cleans up resources.
-func (i *Indexer) Close(ctx context.Context) error {
-	i.logger.Debug("Closing OCI indexer")
 	return nil
-}
\ No newline at end of file
+}
@@ -15,7 +15,7 @@ import (
 
 func TestOCIIndexer_Init(t *testing.T) {
 	logger := zap.NewNop()
-	
+
 	tests := []struct {
 		name        string
 		opts        IndexerOptions
@@ -49,7 +49,7 @@ func TestOCIIndexer_Init(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			indexer := NewIndexer(logger, tt.opts)
 			err := indexer.Init(context.Background())
-			
+
 			if tt.expectError && err == nil {
 				t.Errorf("expected error but got none")
 			}
@@ -60,65 +60,36 @@ func TestOCIIndexer_Init(t *testing.T) {
 	}
 }
 
-func TestOCIIndexer_Get(t *testing.T) {
+func TestOCIIndexer_Get_NotInitialized(t *testing.T) {
 	logger := zap.NewNop()
 	opts := IndexerOptions{
 		Registry:   "registry.example.com",
 		Repository: "packages",
 	}
-	
-	indexer := NewIndexer(logger, opts)
-	err := indexer.Init(context.Background())
-	if err != nil {
-		t.Fatalf("failed to initialize indexer: %v", err)
-	}
-
-	packages, err := indexer.Get(context.Background(), nil)
-	if err != nil {
-		t.Fatalf("failed to get packages: %v", err)
-	}
-
-	if len(packages) != 1 {
-		t.Errorf("expected 1 package, got %d", len(packages))
-	}
 
-	pkg := packages[0]
-	if pkg.Name != "oci-mock-package" {
-		t.Errorf("expected package name 'oci-mock-package', got '%s'", pkg.Name)
-	}
-
-	if pkg.Version != "1.0.0" {
-		t.Errorf("expected package version '1.0.0', got '%s'", pkg.Version)
-	}
+	indexer := NewIndexer(logger, opts)
 
-	if pkg.Type != "integration" {
-		t.Errorf("expected package type 'integration', got '%s'", pkg.Type)
+	if _, err := indexer.Get(context.Background(), nil); err == nil {
+		t.Errorf("expected error getting packages from an uninitialized indexer")
 	}
 }
 
-func TestOCIIndexer_GetWithFilter(t *testing.T) {
+func TestOCIIndexer_Get_UnreachableRegistry(t *testing.T) {
+	// registry.example.com is a reserved, non-routable domain: listing tags
+	// against it must fail rather than silently returning no packages.
 	logger := zap.NewNop()
 	opts := IndexerOptions{
 		Registry:   "registry.example.com",
 		Repository: "packages",
 	}
-	
+
 	indexer := NewIndexer(logger, opts)
-	err := indexer.Init(context.Background())
-	if err != nil {
+	if err := indexer.Init(context.Background()); err != nil {
 		t.Fatalf("failed to initialize indexer: %v", err)
 	}
 
-	filter := &packages.Filter{}
-	getOpts := &packages.GetOptions{Filter: filter}
-	
-	packages, err := indexer.Get(context.Background(), getOpts)
-	if err != nil {
-		t.Fatalf("failed to get packages with filter: %v", err)
-	}
-
-	if len(packages) != 1 {
-		t.Errorf("expected 1 package with filter, got %d", len(packages))
+	if _, err := indexer.Get(context.Background(), &packages.GetOptions{Filter: &packages.Filter{}}); err == nil {
+		t.Errorf("expected error listing tags from an unreachable registry")
 	}
 }
 
@@ -128,10 +99,10 @@ func TestOCIIndexer_Close(t *testing.T) {
 		Registry:   "registry.example.com",
 		Repository: "packages",
 	}
-	
+
 	indexer := NewIndexer(logger, opts)
 	err := indexer.Close(context.Background())
 	if err != nil {
 		t.Errorf("unexpected error closing indexer: %v", err)
 	}
-}
\ No newline at end of file
+}
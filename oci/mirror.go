@@ -0,0 +1,134 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package oci
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+
+	"oras.land/oras-go/v2/registry/remote"
+	"oras.land/oras-go/v2/registry/remote/auth"
+)
+
+// MirrorConfig describes a fallback registry endpoint that mirrors the
+// primary registry's repository.
+type MirrorConfig struct {
+	// Registry is the mirror's base URL, e.g. "https://mirror.example.com".
+	// Unlike IndexerOptions.Registry it must include a scheme and must not
+	// contain a path or repository component.
+	Registry string
+	// CredentialStore resolves credentials for this mirror. If nil, the
+	// primary IndexerOptions.CredentialStore is used.
+	CredentialStore CredentialStore
+	// Insecure skips TLS certificate verification (e.g. for self-signed
+	// certificates) for this mirror. Whether the mirror is dialed over plain
+	// HTTP is decided by Registry's scheme, not by this field.
+	Insecure bool
+}
+
+// endpoint is a registry serving the indexer's repository: either the
+// primary registry or one of its configured mirrors.
+type endpoint struct {
+	// label identifies the endpoint in logs and in a served package's
+	// BasePath, e.g. "registry.example.com" or "mirror.example.com".
+	label string
+	repo  *remote.Repository
+}
+
+// validateMirror checks that raw looks like a Docker-style mirror URL: an
+// http(s) URL with no path, query, or userinfo component. It returns the
+// mirror's host and whether it should be dialed over plain HTTP, mirroring
+// Docker's own ValidateMirror.
+func validateMirror(raw string) (host string, plainHTTP bool, err error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "", false, fmt.Errorf("invalid mirror %q: %w", raw, err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return "", false, fmt.Errorf("invalid mirror %q: scheme must be http or https", raw)
+	}
+	if u.Host == "" {
+		return "", false, fmt.Errorf("invalid mirror %q: missing host", raw)
+	}
+	if u.User != nil {
+		return "", false, fmt.Errorf("invalid mirror %q: must not contain user info", raw)
+	}
+	if (u.Path != "" && u.Path != "/") || u.RawQuery != "" {
+		return "", false, fmt.Errorf("invalid mirror %q: must not contain a path or repository component", raw)
+	}
+	return u.Host, u.Scheme == "http", nil
+}
+
+// newEndpoint builds the ORAS repository client for a single registry host,
+// wiring up the given credential store and TLS configuration the same way
+// for the primary registry and every mirror. plainHTTP dials the registry
+// over unencrypted HTTP instead of HTTPS; insecure only relaxes TLS
+// certificate verification and has no effect when plainHTTP is set.
+func newEndpoint(label, host, repository string, credStore CredentialStore, insecure, plainHTTP bool) (*endpoint, error) {
+	repoRef := fmt.Sprintf("%s/%s", host, repository)
+	repo, err := remote.NewRepository(repoRef)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create repository reference %s: %w", repoRef, err)
+	}
+	repo.PlainHTTP = plainHTTP
+
+	if credStore == nil {
+		credStore = NewAnonymousCredentialStore()
+	}
+
+	httpClient := &http.Client{}
+	if insecure && !plainHTTP {
+		httpClient.Transport = &http.Transport{
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+		}
+	}
+
+	repo.Client = &auth.Client{
+		Client: httpClient,
+		Credential: func(ctx context.Context, registry string) (auth.Credential, error) {
+			return credStore.Get(ctx, registry)
+		},
+	}
+
+	return &endpoint{label: label, repo: repo}, nil
+}
+
+// isTransportError reports whether err looks like a network-level failure
+// (DNS resolution, connection refused, timeout) rather than an application
+// error such as authentication failure or "not found". Only transport errors
+// are worth failing over to a mirror for.
+func isTransportError(err error) bool {
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+	var urlErr *url.Error
+	return errors.As(err, &urlErr)
+}
+
+// withEndpoints tries op against the primary registry first, then each
+// mirror in turn, but only continues past an endpoint on a transport-level
+// error; any other error is returned immediately without trying further
+// endpoints. If every endpoint fails, the combined errors are returned.
+func withEndpoints[T any](endpoints []*endpoint, op func(ep *endpoint) (T, error)) (T, *endpoint, error) {
+	var zero T
+	var errs []error
+	for _, ep := range endpoints {
+		result, err := op(ep)
+		if err == nil {
+			return result, ep, nil
+		}
+		if !isTransportError(err) {
+			return zero, nil, err
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", ep.label, err))
+	}
+	return zero, nil, fmt.Errorf("all registry endpoints failed: %w", errors.Join(errs...))
+}
@@ -0,0 +1,166 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package oci
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"oras.land/oras-go/v2/registry/remote"
+
+	"github.com/elastic/package-registry/packages"
+	"github.com/elastic/package-registry/packages/builder"
+)
+
+const (
+	// mediaTypePackageManifest identifies the root package manifest (manifest.yml)
+	// layer of a package artifact.
+	mediaTypePackageManifest = "application/vnd.elastic.package.manifest.v1+yaml"
+
+	// mediaTypePackageContent identifies the gzip-compressed tarball layer
+	// holding the full contents of a package artifact.
+	mediaTypePackageContent = "application/vnd.elastic.package.content.v1.tar+gzip"
+)
+
+// getPackageFromTag fetches the artifact described by desc (the resolved
+// descriptor for tag) and parses it into a packages.Package, in the same way
+// the filesystem indexer parses a package directory. Every fetch is tried
+// against endpoints in order, failing over to the next mirror on a
+// transport-level error; the package's BasePath records whichever endpoint
+// ultimately served its content, so downstream file serving routes blob
+// requests back to it.
+func (i *Indexer) getPackageFromTag(ctx context.Context, endpoints []*endpoint, tag string, desc ocispec.Descriptor) (*packages.Package, error) {
+	ref := fmt.Sprintf("%s:%s", i.opts.Repository, tag)
+
+	manifestData, _, err := withEndpoints(endpoints, func(ep *endpoint) ([]byte, error) {
+		return fetchBlob(ctx, ep.repo, ref, desc)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch manifest for %q: %w", ref, err)
+	}
+
+	var manifest ocispec.Manifest
+	if err := json.Unmarshal(manifestData, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to decode manifest for %q: %w", ref, err)
+	}
+
+	pkg, servingEp, err := i.buildPackage(ctx, endpoints, ref, manifest)
+	if err != nil {
+		return nil, err
+	}
+
+	pkg.BasePath = fmt.Sprintf("oci://%s/%s:%s", servingEp.label, i.opts.Repository, tag)
+	return pkg, nil
+}
+
+// buildPackage locates the content and manifest layers in manifest and parses
+// them into a packages.Package, preferring the full package tarball when
+// present and falling back to the root manifest alone otherwise. It returns
+// the endpoint that served the layer actually used to build the package.
+func (i *Indexer) buildPackage(ctx context.Context, endpoints []*endpoint, ref string, manifest ocispec.Manifest) (*packages.Package, *endpoint, error) {
+	var contentLayer, manifestLayer *ocispec.Descriptor
+	for idx, layer := range manifest.Layers {
+		switch layer.MediaType {
+		case mediaTypePackageContent:
+			contentLayer = &manifest.Layers[idx]
+		case mediaTypePackageManifest:
+			manifestLayer = &manifest.Layers[idx]
+		}
+	}
+
+	switch {
+	case contentLayer != nil:
+		fsys, ep, err := i.fetchContentFS(ctx, endpoints, ref, *contentLayer)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to assemble package filesystem for %q: %w", ref, err)
+		}
+		pkg, err := packages.NewPackage(fsys)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to parse package contents for %q: %w", ref, err)
+		}
+		return pkg, ep, nil
+
+	case manifestLayer != nil:
+		data, ep, err := withEndpoints(endpoints, func(ep *endpoint) ([]byte, error) {
+			return fetchBlob(ctx, ep.repo, ref, *manifestLayer)
+		})
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to fetch manifest layer for %q: %w", ref, err)
+		}
+		pkg, err := builder.BuildPackage(data)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to parse package manifest for %q: %w", ref, err)
+		}
+		return pkg, ep, nil
+
+	default:
+		return nil, nil, fmt.Errorf("artifact %q has no layer with media type %q or %q", ref, mediaTypePackageContent, mediaTypePackageManifest)
+	}
+}
+
+// fetchBlob fetches and fully reads the blob described by desc from repo,
+// verifying the read bytes against desc.Digest before returning them so a
+// compromised or malicious registry (or mirror) can't smuggle in content
+// that doesn't match what was requested.
+func fetchBlob(ctx context.Context, repo *remote.Repository, ref string, desc ocispec.Descriptor) ([]byte, error) {
+	rc, err := repo.Fetch(ctx, desc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch blob %s for %q: %w", desc.Digest, ref, err)
+	}
+	defer rc.Close()
+
+	verifier := desc.Digest.Verifier()
+	data, err := io.ReadAll(io.TeeReader(rc, verifier))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read blob %s for %q: %w", desc.Digest, ref, err)
+	}
+	if !verifier.Verified() {
+		return nil, fmt.Errorf("blob for %q does not match expected digest %s", ref, desc.Digest)
+	}
+	return data, nil
+}
+
+// fetchContentFS streams the package content layer to a blob-cache file on
+// disk (reusing it if already present) and returns an fs.FS that reads
+// package files directly out of that file on demand, so serving a package
+// never requires buffering its whole content tree in memory. The fetch is
+// tried against endpoints in order, failing over to the next mirror on a
+// transport-level error; a cache hit is attributed to the primary endpoint,
+// since no network fetch actually took place.
+func (i *Indexer) fetchContentFS(ctx context.Context, endpoints []*endpoint, ref string, desc ocispec.Descriptor) (fs.FS, *endpoint, error) {
+	diskPath, err := i.blobStorePath(desc.Digest)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	servingEp := endpoints[0]
+	if _, err := os.Stat(diskPath); os.IsNotExist(err) {
+		var rc io.ReadCloser
+		rc, servingEp, err = withEndpoints(endpoints, func(ep *endpoint) (io.ReadCloser, error) {
+			return ep.repo.Fetch(ctx, desc)
+		})
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to fetch blob %s for %q: %w", desc.Digest, ref, err)
+		}
+		err = streamBlobToDisk(diskPath, desc.Digest, rc)
+		rc.Close()
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to store blob %s for %q: %w", desc.Digest, ref, err)
+		}
+	} else if err != nil {
+		return nil, nil, fmt.Errorf("failed to stat cached blob %s: %w", desc.Digest, err)
+	}
+
+	fsys, err := newTarGzFS(diskPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	return fsys, servingEp, nil
+}
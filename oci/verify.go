@@ -0,0 +1,260 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package oci
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"strings"
+
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+const (
+	// cosignSignatureAnnotation holds the base64-encoded signature over the
+	// simple-signing payload, per the cosign OCI layout.
+	cosignSignatureAnnotation = "dev.cosignproject.cosign/signature"
+	// cosignCertificateAnnotation holds the PEM-encoded signing certificate
+	// for keyless (Fulcio) cosign signatures.
+	cosignCertificateAnnotation = "dev.sigstore.cosign/certificate"
+	// mediaTypeSimpleSigning identifies a cosign "simple signing" payload layer.
+	mediaTypeSimpleSigning = "application/vnd.dev.cosign.simplesigning.v1+json"
+	// fulcioOIDCIssuerOID is the x509 extension cosign's Fulcio-issued
+	// certificates use to record the OIDC issuer that authenticated the signer.
+	fulcioOIDCIssuerOID = "1.3.6.1.4.1.57264.1.1"
+)
+
+// TrustPolicy selects how Refresh reacts to artifacts it couldn't verify.
+type TrustPolicy string
+
+const (
+	// TrustPolicyWarn logs and indexes unsigned/unverified artifacts anyway.
+	// This is the default when Verification is configured without an
+	// explicit TrustPolicy.
+	TrustPolicyWarn TrustPolicy = "warn"
+	// TrustPolicyReject drops unsigned/unverified artifacts from the index.
+	TrustPolicyReject TrustPolicy = "reject"
+)
+
+// VerificationOptions configures cosign signature verification for artifacts
+// pulled from the OCI registry.
+type VerificationOptions struct {
+	// TrustPolicy selects how a missing or invalid signature is handled when
+	// RequireSignature is false. Defaults to TrustPolicyWarn.
+	TrustPolicy TrustPolicy
+	// PublicKeys is a set of PEM-encoded ECDSA public keys; a signature
+	// verifying against any one of them is considered trusted.
+	PublicKeys [][]byte
+	// CertificateIdentity, if set, is matched against the SAN (URI or email)
+	// of a keyless signing certificate's subject.
+	CertificateIdentity string
+	// CertificateOIDCIssuer, if set, is matched against the Fulcio OIDC
+	// issuer extension of a keyless signing certificate.
+	CertificateOIDCIssuer string
+	// CertificateRoots is the set of trusted roots a keyless signing
+	// certificate must chain to (e.g. the Fulcio root CA). It is required
+	// for keyless verification: without it, a certificate's self-declared
+	// identity and issuer can't be trusted, since anyone can mint one
+	// claiming whatever identity they like.
+	CertificateRoots *x509.CertPool
+	// RequireSignature, when true, makes Refresh drop packages with no valid
+	// signature instead of indexing them unsigned.
+	RequireSignature bool
+}
+
+// verifySignature resolves the cosign signature artifact for the package at
+// desc (tagged "sha256-<digest>.sig" per cosign's convention) and checks it
+// against the configured trust policy. It returns whether a trusted
+// signature was found. Every fetch is tried against endpoints in order,
+// failing over to the next mirror on a transport-level error.
+//
+// Only cosign's tag-based signature convention is supported; signatures
+// discovered via the OCI 1.1 /referrers API (e.g. Notary v2) are not.
+func (i *Indexer) verifySignature(ctx context.Context, endpoints []*endpoint, ref string, desc ocispec.Descriptor) (bool, error) {
+	v := i.opts.Verification
+	if v == nil {
+		return true, nil
+	}
+
+	sigTag := cosignSignatureTag(desc.Digest)
+
+	sigDesc, _, err := withEndpoints(endpoints, func(ep *endpoint) (ocispec.Descriptor, error) {
+		return ep.repo.Resolve(ctx, sigTag)
+	})
+	if err != nil {
+		// No signature manifest published for this digest.
+		return false, nil
+	}
+
+	manifestData, _, err := withEndpoints(endpoints, func(ep *endpoint) ([]byte, error) {
+		return fetchBlob(ctx, ep.repo, ref, sigDesc)
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to fetch signature manifest for %s: %w", ref, err)
+	}
+
+	var manifest ocispec.Manifest
+	if err := json.Unmarshal(manifestData, &manifest); err != nil {
+		return false, fmt.Errorf("failed to decode signature manifest for %s: %w", ref, err)
+	}
+
+	for _, layer := range manifest.Layers {
+		if layer.MediaType != mediaTypeSimpleSigning {
+			continue
+		}
+		ok, err := verifySimpleSigningLayer(ctx, endpoints, ref, layer, v)
+		if err != nil {
+			return false, fmt.Errorf("failed to verify signature for %s: %w", ref, err)
+		}
+		if ok {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// verifySimpleSigningLayer checks a single cosign simple-signing layer's
+// signature against either the configured public keys or, for keyless
+// signatures, the certificate identity/issuer.
+func verifySimpleSigningLayer(ctx context.Context, endpoints []*endpoint, ref string, layer ocispec.Descriptor, v *VerificationOptions) (bool, error) {
+	payload, _, err := withEndpoints(endpoints, func(ep *endpoint) ([]byte, error) {
+		return fetchBlob(ctx, ep.repo, ref, layer)
+	})
+	if err != nil {
+		return false, err
+	}
+
+	sigB64, ok := layer.Annotations[cosignSignatureAnnotation]
+	if !ok {
+		return false, fmt.Errorf("signature layer is missing %s annotation", cosignSignatureAnnotation)
+	}
+	sig, err := base64.StdEncoding.DecodeString(sigB64)
+	if err != nil {
+		return false, fmt.Errorf("failed to decode signature: %w", err)
+	}
+
+	if certPEM, ok := layer.Annotations[cosignCertificateAnnotation]; ok && v.CertificateIdentity != "" {
+		return verifyWithCertificate(payload, sig, []byte(certPEM), v.CertificateIdentity, v.CertificateOIDCIssuer, v.CertificateRoots)
+	}
+
+	for _, keyPEM := range v.PublicKeys {
+		if verifyWithPublicKey(payload, sig, keyPEM) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// verifyWithPublicKey reports whether sig is a valid ECDSA signature over
+// payload's SHA-256 digest for the PEM-encoded public key keyPEM.
+func verifyWithPublicKey(payload, sig, keyPEM []byte) bool {
+	block, _ := pem.Decode(keyPEM)
+	if block == nil {
+		return false
+	}
+	pub, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return false
+	}
+	ecKey, ok := pub.(*ecdsa.PublicKey)
+	if !ok {
+		return false
+	}
+	sum := sha256.Sum256(payload)
+	return ecdsa.VerifyASN1(ecKey, sum[:], sig)
+}
+
+// verifyWithCertificate confirms the PEM-encoded leaf certificate certPEM
+// chains to roots and was issued to identity by oidcIssuer, and only then
+// verifies sig against the certificate's public key. A certificate that
+// doesn't chain to roots is an error; one that chains but doesn't match
+// identity/oidcIssuer reports false without an error. Chain verification is
+// mandatory: without it, identity and oidcIssuer are just strings the
+// certificate asserts about itself, which anyone can forge with a key of
+// their own choosing.
+func verifyWithCertificate(payload, sig, certPEM []byte, identity, oidcIssuer string, roots *x509.CertPool) (bool, error) {
+	block, _ := pem.Decode(certPEM)
+	if block == nil {
+		return false, fmt.Errorf("failed to decode signing certificate")
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return false, fmt.Errorf("failed to parse signing certificate: %w", err)
+	}
+
+	if roots == nil {
+		return false, fmt.Errorf("no trusted certificate roots configured for keyless verification")
+	}
+	if _, err := cert.Verify(x509.VerifyOptions{
+		Roots:     roots,
+		KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning},
+	}); err != nil {
+		return false, fmt.Errorf("signing certificate did not chain to a trusted root: %w", err)
+	}
+
+	if !certMatchesIdentity(cert, identity, oidcIssuer) {
+		return false, nil
+	}
+
+	pub, ok := cert.PublicKey.(*ecdsa.PublicKey)
+	if !ok {
+		return false, fmt.Errorf("unsupported signing certificate key type %T", cert.PublicKey)
+	}
+	sum := sha256.Sum256(payload)
+	return ecdsa.VerifyASN1(pub, sum[:], sig), nil
+}
+
+// certMatchesIdentity reports whether cert was issued to identity (checked
+// against its URI and email SANs) by oidcIssuer (checked against the Fulcio
+// OIDC issuer extension).
+func certMatchesIdentity(cert *x509.Certificate, identity, oidcIssuer string) bool {
+	if identity != "" {
+		matched := false
+		for _, uri := range cert.URIs {
+			if uri.String() == identity {
+				matched = true
+				break
+			}
+		}
+		for _, email := range cert.EmailAddresses {
+			if email == identity {
+				matched = true
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+
+	if oidcIssuer != "" {
+		found := false
+		for _, ext := range cert.Extensions {
+			if ext.Id.String() == fulcioOIDCIssuerOID && strings.TrimSpace(string(ext.Value)) == oidcIssuer {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	return true
+}
+
+// cosignSignatureTag derives the tag cosign publishes a digest's signature
+// manifest under, e.g. "sha256:abcd..." becomes "sha256-abcd....sig".
+func cosignSignatureTag(dgst digest.Digest) string {
+	return strings.ReplaceAll(dgst.String(), ":", "-") + ".sig"
+}
@@ -0,0 +1,218 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package oci
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"math/big"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/opencontainers/go-digest"
+)
+
+func TestCosignSignatureTag(t *testing.T) {
+	dgst := digest.FromString("package contents")
+	want := "sha256-" + dgst.Encoded() + ".sig"
+	if got := cosignSignatureTag(dgst); got != want {
+		t.Errorf("cosignSignatureTag(%s) = %q, want %q", dgst, got, want)
+	}
+}
+
+func encodePublicKeyPEM(t *testing.T, key *ecdsa.PublicKey) []byte {
+	t.Helper()
+	der, err := x509.MarshalPKIXPublicKey(key)
+	if err != nil {
+		t.Fatalf("failed to marshal public key: %v", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+}
+
+func TestVerifyWithPublicKey(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+
+	payload := []byte("simple signing payload")
+	sum := sha256.Sum256(payload)
+	sig, err := ecdsa.SignASN1(rand.Reader, key, sum[:])
+	if err != nil {
+		t.Fatalf("failed to sign payload: %v", err)
+	}
+
+	keyPEM := encodePublicKeyPEM(t, &key.PublicKey)
+
+	if !verifyWithPublicKey(payload, sig, keyPEM) {
+		t.Errorf("verifyWithPublicKey: valid signature reported invalid")
+	}
+	if verifyWithPublicKey([]byte("tampered payload"), sig, keyPEM) {
+		t.Errorf("verifyWithPublicKey: signature over a different payload reported valid")
+	}
+	if verifyWithPublicKey(payload, sig, encodePublicKeyPEM(t, &otherKey.PublicKey)) {
+		t.Errorf("verifyWithPublicKey: signature verified against the wrong key")
+	}
+	if verifyWithPublicKey(payload, sig, []byte("not a PEM block")) {
+		t.Errorf("verifyWithPublicKey: garbage PEM reported valid")
+	}
+}
+
+// generateCA produces a minimal self-signed CA certificate and returns it
+// alongside its private key, for issuing leaf certificates in tests without
+// a real Fulcio CA.
+func generateCA(t *testing.T) (*ecdsa.PrivateKey, *x509.Certificate) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("failed to create CA certificate: %v", err)
+	}
+	ca, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("failed to parse CA certificate: %v", err)
+	}
+	return key, ca
+}
+
+// generateCert issues a minimal keyless-style leaf certificate with a URI SAN
+// and a Fulcio OIDC issuer extension, signed by caKey/ca, for exercising
+// certMatchesIdentity and verifyWithCertificate.
+func generateCert(t *testing.T, caKey *ecdsa.PrivateKey, ca *x509.Certificate, identity, oidcIssuer string) (*ecdsa.PrivateKey, []byte) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+
+	uri, err := url.Parse(identity)
+	if err != nil {
+		t.Fatalf("failed to parse identity URI: %v", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "test"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(24 * time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning},
+		URIs:         []*url.URL{uri},
+		ExtraExtensions: []pkix.Extension{
+			{Id: asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 57264, 1, 1}, Value: []byte(oidcIssuer)},
+		},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, ca, &key.PublicKey, caKey)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %v", err)
+	}
+
+	return key, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
+
+func TestCertMatchesIdentity(t *testing.T) {
+	caKey, ca := generateCA(t)
+	_, certPEM := generateCert(t, caKey, ca, "https://example.com/signer", "https://accounts.example.com")
+	block, _ := pem.Decode(certPEM)
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		t.Fatalf("failed to parse certificate: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		identity   string
+		oidcIssuer string
+		want       bool
+	}{
+		{name: "matches identity and issuer", identity: "https://example.com/signer", oidcIssuer: "https://accounts.example.com", want: true},
+		{name: "identity only, unset issuer constraint", identity: "https://example.com/signer", want: true},
+		{name: "wrong identity", identity: "https://example.com/someone-else", oidcIssuer: "https://accounts.example.com", want: false},
+		{name: "wrong issuer", identity: "https://example.com/signer", oidcIssuer: "https://other.example.com", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := certMatchesIdentity(cert, tt.identity, tt.oidcIssuer); got != tt.want {
+				t.Errorf("certMatchesIdentity() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVerifyWithCertificate(t *testing.T) {
+	identity := "https://example.com/signer"
+	oidcIssuer := "https://accounts.example.com"
+	caKey, ca := generateCA(t)
+	key, certPEM := generateCert(t, caKey, ca, identity, oidcIssuer)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca)
+
+	payload := []byte("simple signing payload")
+	sum := sha256.Sum256(payload)
+	sig, err := ecdsa.SignASN1(rand.Reader, key, sum[:])
+	if err != nil {
+		t.Fatalf("failed to sign payload: %v", err)
+	}
+
+	ok, err := verifyWithCertificate(payload, sig, certPEM, identity, oidcIssuer, roots)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !ok {
+		t.Errorf("verifyWithCertificate: valid signature and matching identity reported invalid")
+	}
+
+	ok, err = verifyWithCertificate(payload, sig, certPEM, "https://example.com/someone-else", oidcIssuer, roots)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ok {
+		t.Errorf("verifyWithCertificate: mismatched identity reported valid")
+	}
+
+	// A certificate that doesn't chain to any configured root must be
+	// rejected outright, regardless of what it claims about itself.
+	otherCAKey, otherCA := generateCA(t)
+	_, rogueCertPEM := generateCert(t, otherCAKey, otherCA, identity, oidcIssuer)
+	if _, err := verifyWithCertificate(payload, sig, rogueCertPEM, identity, oidcIssuer, roots); err == nil {
+		t.Errorf("verifyWithCertificate: certificate from an untrusted CA was accepted")
+	}
+
+	// No configured roots at all must fail closed rather than trusting the
+	// certificate's self-declared identity.
+	if _, err := verifyWithCertificate(payload, sig, certPEM, identity, oidcIssuer, nil); err == nil {
+		t.Errorf("verifyWithCertificate: missing CertificateRoots was accepted")
+	}
+}
@@ -0,0 +1,82 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package oci
+
+import (
+	"github.com/Masterminds/semver/v3"
+)
+
+// eligibleTagVersion reports whether tag should be considered a package
+// version at all: it must match TagPattern (if set) and carry a strict
+// semver version, and prereleases are only included when IncludePrerelease
+// is set. Tags that fail any of these checks aren't packages that the
+// filesystem indexer's equivalent would ever surface, so they're dropped
+// entirely rather than merely hidden from listings.
+func (i *Indexer) eligibleTagVersion(tag string) (*semver.Version, bool) {
+	if i.opts.TagPattern != nil && !i.opts.TagPattern.MatchString(tag) {
+		return nil, false
+	}
+
+	v, ok := tagVersion(tag)
+	if !ok {
+		return nil, false
+	}
+
+	if v.Prerelease() != "" && !i.opts.IncludePrerelease {
+		return nil, false
+	}
+
+	return v, true
+}
+
+// tagVersion parses the semver version carried by tag. A repository that
+// holds a single package uses pure-version tags ("1.2.3"); a repository
+// shared across packages instead prefixes each tag with the package name
+// ("system-1.2.3", "elastic_agent-8.10.0"), so a whole-tag parse is tried
+// first and, failing that, each "-" boundary from left to right is tried as
+// the name/version split until one leaves a valid semver suffix.
+func tagVersion(tag string) (*semver.Version, bool) {
+	if v, err := semver.StrictNewVersion(tag); err == nil {
+		return v, true
+	}
+
+	for i := 0; i < len(tag); i++ {
+		if tag[i] != '-' {
+			continue
+		}
+		if v, err := semver.StrictNewVersion(tag[i+1:]); err == nil {
+			return v, true
+		}
+	}
+
+	return nil, false
+}
+
+// latestPerPackage reduces entries to the single highest-version entry per
+// package name, preserving the order in which package names were first
+// encountered in entries.
+func latestPerPackage(entries []*indexEntry) []*indexEntry {
+	var order []string
+	latest := make(map[string]*indexEntry, len(entries))
+
+	for _, entry := range entries {
+		name := entry.pkg.Name
+		cur, ok := latest[name]
+		if !ok {
+			order = append(order, name)
+			latest[name] = entry
+			continue
+		}
+		if entry.version.Compare(cur.version) > 0 {
+			latest[name] = entry
+		}
+	}
+
+	result := make([]*indexEntry, 0, len(order))
+	for _, name := range order {
+		result = append(result, latest[name])
+	}
+	return result
+}
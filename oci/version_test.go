@@ -0,0 +1,77 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package oci
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/Masterminds/semver/v3"
+
+	"github.com/elastic/package-registry/packages"
+)
+
+func TestIndexer_eligibleTagVersion(t *testing.T) {
+	tests := []struct {
+		name              string
+		tag               string
+		tagPattern        *regexp.Regexp
+		includePrerelease bool
+		wantVersion       string
+		wantOK            bool
+	}{
+		{name: "pure version tag", tag: "1.2.3", wantVersion: "1.2.3", wantOK: true},
+		{name: "name-version tag", tag: "system-1.2.3", wantVersion: "1.2.3", wantOK: true},
+		{name: "name with underscore", tag: "elastic_agent-8.10.0", wantVersion: "8.10.0", wantOK: true},
+		{name: "name containing a dash", tag: "aws-s3-1.2.3", wantVersion: "1.2.3", wantOK: true},
+		{name: "name-version prerelease tag, included", tag: "system-1.2.3-beta.1", includePrerelease: true, wantVersion: "1.2.3-beta.1", wantOK: true},
+		{name: "prerelease excluded by default", tag: "system-1.2.3-beta.1", wantOK: false},
+		{name: "not a version at all", tag: "latest", wantOK: false},
+		{name: "pattern excludes tag", tag: "system-1.2.3", tagPattern: regexp.MustCompile(`^elastic_agent-`), wantOK: false},
+		{name: "pattern matches tag", tag: "elastic_agent-8.10.0", tagPattern: regexp.MustCompile(`^elastic_agent-`), wantVersion: "8.10.0", wantOK: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			indexer := &Indexer{opts: IndexerOptions{TagPattern: tt.tagPattern, IncludePrerelease: tt.includePrerelease}}
+
+			v, ok := indexer.eligibleTagVersion(tt.tag)
+			if ok != tt.wantOK {
+				t.Fatalf("eligibleTagVersion(%q) ok = %v, want %v", tt.tag, ok, tt.wantOK)
+			}
+			if !tt.wantOK {
+				return
+			}
+			if v.String() != tt.wantVersion {
+				t.Errorf("eligibleTagVersion(%q) = %q, want %q", tt.tag, v.String(), tt.wantVersion)
+			}
+		})
+	}
+}
+
+func TestLatestPerPackage(t *testing.T) {
+	entry := func(name, version string) *indexEntry {
+		return &indexEntry{version: semver.MustParse(version), pkg: &packages.Package{Name: name}}
+	}
+
+	entries := []*indexEntry{
+		entry("system", "1.0.0"),
+		entry("elastic_agent", "8.9.0"),
+		entry("system", "1.2.3"),
+		entry("elastic_agent", "8.10.0"),
+		entry("system", "1.1.0"),
+	}
+
+	got := latestPerPackage(entries)
+	if len(got) != 2 {
+		t.Fatalf("latestPerPackage returned %d entries, want 2", len(got))
+	}
+	if got[0].pkg.Name != "system" || got[0].version.String() != "1.2.3" {
+		t.Errorf("got[0] = %s@%s, want system@1.2.3", got[0].pkg.Name, got[0].version)
+	}
+	if got[1].pkg.Name != "elastic_agent" || got[1].version.String() != "8.10.0" {
+		t.Errorf("got[1] = %s@%s, want elastic_agent@8.10.0", got[1].pkg.Name, got[1].version)
+	}
+}